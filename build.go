@@ -0,0 +1,204 @@
+package mdoc
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Build renders dir's Markdown document tree to a complete static HTML
+// tree rooted at outDir. Each Markdown file is rendered to its own
+// index.html so links resolve without a running server, directory
+// listings are rewritten to match, and the configured theme's assets
+// are copied under .mdoc/assets/.
+func Build(dir, outDir string, opts ...Option) error {
+	if dir == "" {
+		dir = "."
+	}
+	h := newHandler(dir, os.DirFS(dir), opts...)
+	return h.buildTree(outDir)
+}
+
+// BuildFS renders fsys's Markdown document tree to outDir, exactly as
+// Build does for a directory on disk.
+func BuildFS(fsys fs.FS, outDir string, opts ...Option) error {
+	h := newHandler("", fsys, opts...)
+	return h.buildTree(outDir)
+}
+
+func (h *handler) buildTree(outDir string) error {
+	if err := h.buildDir(outDir, ".", "/"); err != nil {
+		return err
+	}
+	return h.buildAssets(outDir)
+}
+
+// buildDir renders the directory listing (or its index.md, per the
+// same rule ServeHTTP applies) for dir and recurses into its children.
+func (h *handler) buildDir(outDir, dir, urlPath string) error {
+	files, err := getFiles(h.fsys, dir, h.drafts)
+	if err != nil {
+		return err
+	}
+	hasIndex := false
+	for _, file := range files {
+		if !file.IsDir && file.Name == "index.md" {
+			hasIndex = true
+			break
+		}
+	}
+	if hasIndex {
+		err = h.buildDocument(outDir, path.Join(dir, "index.md"), urlPath)
+	} else {
+		var b []byte
+		b, err = h.renderIndex(files, urlPath)
+		if err == nil {
+			err = writeFile(filepath.Join(outDir, filepath.FromSlash(urlPath), "index.html"), rewriteMarkdownLinks(b, dir))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		childPath := path.Join(urlPath, file.Name)
+		switch {
+		case file.IsDir:
+			err = h.buildDir(outDir, path.Join(dir, file.Name), childPath+"/")
+		case file.IsWalk:
+			err = h.buildWalk(outDir, path.Join(dir, file.Name))
+		case file.Name != "index.md":
+			err = h.buildDocument(outDir, path.Join(dir, file.Name), childPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildWalk renders the codewalk at walkPath to outDir/.mdoc/walk/name,
+// mirroring the path h.walks() serves it at live, so that the WalkURL
+// links an index listing renders resolve in the static tree too.
+// walkPath is the caller's own path (buildDir already walked it),
+// rather than a name re-resolved via findWalk's fs-wide search: that
+// search returns whichever matching file it visits first, so passing
+// the caller's own path at least guarantees the content rendered to
+// name's output path is the same file WalkURL's caller was iterating
+// when it decided to link to name. Codewalks share a single, flat
+// /.mdoc/walk/name namespace throughout mdoc (WalkURL itself does not
+// carry a directory), so two walk files in different directories that
+// strip to the same name still collide on that one output path - the
+// same ambiguity findWalk already has live.
+func (h *handler) buildWalk(outDir, walkPath string) error {
+	name := walkName(walkPath)
+	b, err := h.renderWalkFile(walkPath, path.Join(h.root, ".mdoc/walk", name))
+	if err != nil {
+		return err
+	}
+	b = rewriteMarkdownLinks(b, path.Dir(walkPath))
+	return writeFile(filepath.Join(outDir, ".mdoc", "walk", name), b)
+}
+
+// buildDocument renders the Markdown file at name to outDir, using a
+// pretty-URL layout: doc.md becomes doc/index.html, while index.md
+// renders in place of its directory's listing.
+func (h *handler) buildDocument(outDir, name, urlPath string) error {
+	raw, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return err
+	}
+	b, err := h.renderDocument(path.Base(name), raw, urlPath)
+	if err != nil {
+		return err
+	}
+	dir := urlPath
+	if path.Base(name) != "index.md" {
+		dir = strings.TrimSuffix(urlPath, path.Ext(urlPath))
+	}
+	return writeFile(filepath.Join(outDir, filepath.FromSlash(dir), "index.html"), rewriteMarkdownLinks(b, path.Dir(name)))
+}
+
+// mdLinkPattern matches a relative or root-relative href ending in
+// .md, with an optional #fragment: the shape of the links
+// goldmark-rendered document content and the default index theme both
+// produce. The path is required to exclude ':' so that scheme-
+// qualified links (http:, mailto:, and the like) are left untouched.
+var mdLinkPattern = regexp.MustCompile(`href="([^":#]+)\.md(#[^"]*)?"`)
+
+// rewriteMarkdownLinks rewrites .md hrefs in rendered build output to
+// the pretty-URL paths buildDir and buildDocument actually write their
+// targets to, so that cross-referencing links resolve on a plain
+// static host. base is the source directory the rendered page itself
+// came from (not its pretty-URL output directory, which gains an
+// extra path segment for any page but an index.md): links are authored
+// relative to the source tree, so resolving against base first and
+// emitting a root-relative href sidesteps having to account for that
+// extra segment at every link site. ServeHTTP needs no equivalent
+// pass: it serves documents at their literal .md paths, matching the
+// links as rendered.
+func rewriteMarkdownLinks(b []byte, base string) []byte {
+	return mdLinkPattern.ReplaceAllFunc(b, func(m []byte) []byte {
+		sub := mdLinkPattern.FindSubmatch(m)
+		link := string(sub[1])
+		if !path.IsAbs(link) {
+			link = path.Join(base, link)
+		}
+		return []byte(`href="` + prettyHref(link) + string(sub[2]) + `"`)
+	})
+}
+
+// prettyHref converts a source path, with its .md suffix already
+// stripped and with or without a leading slash, to the root-relative
+// pretty-URL path Build writes its rendered output to: /doc becomes
+// /doc/, while a path ending in index collapses to its parent
+// directory, matching buildDocument's own index.md special case.
+func prettyHref(link string) string {
+	link = strings.TrimPrefix(link, "/")
+	if path.Base(link) == "index" {
+		dir := path.Dir(link)
+		if dir == "." {
+			return "/"
+		}
+		return "/" + dir + "/"
+	}
+	return "/" + link + "/"
+}
+
+// buildAssets copies the configured theme's assets directory to
+// outDir/.mdoc/assets, mirroring the path h.assets() serves live.
+func (h *handler) buildAssets(outDir string) error {
+	if _, err := fs.Stat(h.themeFS, "assets"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	dst := filepath.Join(outDir, ".mdoc", "assets")
+	return fs.WalkDir(h.themeFS, "assets", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, "assets"), "/")
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		b, err := fs.ReadFile(h.themeFS, name)
+		if err != nil {
+			return err
+		}
+		return writeFile(target, b)
+	})
+}
+
+func writeFile(name string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, b, 0644)
+}
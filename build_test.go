@@ -0,0 +1,66 @@
+package mdoc
+
+import "testing"
+
+func TestPrettyHref(t *testing.T) {
+	cases := map[string]string{
+		"other":     "/other/",
+		"sub/index": "/sub/",
+		"index":     "/",
+		"a/b/c":     "/a/b/c/",
+		"/already":  "/already/",
+	}
+	for in, want := range cases {
+		if got := prettyHref(in); got != want {
+			t.Errorf("prettyHref(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRewriteMarkdownLinks(t *testing.T) {
+	cases := []struct {
+		name string
+		base string
+		in   string
+		want string
+	}{
+		{
+			name: "sibling link at root",
+			base: ".",
+			in:   `<a href="other.md">x</a>`,
+			want: `<a href="/other/">x</a>`,
+		},
+		{
+			name: "index link collapses to parent directory",
+			base: "guide",
+			in:   `<a href="index.md">up</a>`,
+			want: `<a href="/guide/">up</a>`,
+		},
+		{
+			name: "parent-relative link out of a subdirectory",
+			base: "guide",
+			in:   `<a href="../index.md">home</a>`,
+			want: `<a href="/">home</a>`,
+		},
+		{
+			name: "fragment is preserved",
+			base: ".",
+			in:   `<a href="other.md#section">x</a>`,
+			want: `<a href="/other/#section">x</a>`,
+		},
+		{
+			name: "scheme-qualified link is untouched",
+			base: ".",
+			in:   `<a href="http://example.com/x.md">x</a>`,
+			want: `<a href="http://example.com/x.md">x</a>`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(rewriteMarkdownLinks([]byte(c.in), c.base))
+			if got != c.want {
+				t.Errorf("got  %s\nwant %s", got, c.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,9 @@ import (
 var (
 	addr  = flag.String("addr", ":3000", "address to listen on")
 	theme = flag.String("theme", defaultTheme, "rendering theme")
+	build = flag.Bool("build", false, "render a static HTML tree to -out instead of serving over HTTP")
+	out   = flag.String("out", "dist", "output directory used by -build and -watch")
+	watch = flag.Duration("watch", 0, "rebuild -out at this interval while serving, 0 to disable")
 	help  = flag.Bool("help", false, "display this usage information")
 )
 
@@ -35,7 +38,18 @@ func main() {
 	if len(args) == 1 {
 		dir = args[0]
 	}
-	m := mdoc.New(dir, mdoc.Theme(*theme))
+	if *build {
+		err := mdoc.Build(dir, *out, themeOption(*theme))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	opts := []mdoc.Option{themeOption(*theme)}
+	if *watch > 0 {
+		opts = append(opts, mdoc.Watch(*out, *watch))
+	}
+	m := mdoc.New(dir, opts...)
 	err := http.ListenAndServe(":3000", m)
 	if err != nil {
 		log.Fatal(err)
@@ -43,6 +57,6 @@ func main() {
 }
 
 func usage(w io.Writer) {
-	fmt.Fprintln(w, "usage: mdoc [-addr=<addr>] [-theme=<theme>] [<dir>]")
+	fmt.Fprintln(w, "usage: mdoc [-addr=<addr>] [-theme=<theme>] [-build] [-out=<dir>] [-watch=<interval>] [<dir>]")
 	flag.PrintDefaults()
 }
@@ -0,0 +1,13 @@
+//go:build !embed
+
+package main
+
+import "github.com/pnelson/mdoc"
+
+// themeOption returns the Option configuring the on-disk theme at dir.
+// Locating the default theme this way requires $GOPATH to contain the
+// mdoc source tree; build with -tags embed to serve a copy of the
+// default theme embedded into the binary instead.
+func themeOption(dir string) mdoc.Option {
+	return mdoc.Theme(dir)
+}
@@ -0,0 +1,24 @@
+//go:build embed
+
+package main
+
+import (
+	"log"
+
+	"github.com/pnelson/mdoc"
+)
+
+// themeOption returns the Option serving the default theme embedded
+// into the binary at build time, so $GOPATH is not required to locate
+// contrib/themes/default at runtime. dir is ignored; build without
+// -tags embed to serve a theme of your choosing from disk. The theme
+// itself is embedded by mdoc.DefaultThemeFS, since //go:embed cannot
+// reach outside cmd/mdoc's own directory to contrib/ at the module
+// root.
+func themeOption(dir string) mdoc.Option {
+	fsys, err := mdoc.DefaultThemeFS()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return mdoc.ThemeFS(fsys)
+}
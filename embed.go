@@ -0,0 +1,25 @@
+//go:build embed
+
+package mdoc
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// embeddedThemeFS embeds contrib/themes/default into the binary when
+// mdoc is built with -tags embed. //go:embed cannot reach outside the
+// directory of the file that declares it, so this lives in the root
+// package alongside contrib/, rather than in cmd/mdoc where the
+// default theme is otherwise located relative to $GOPATH.
+//
+//go:embed all:contrib/themes/default
+var embeddedThemeFS embed.FS
+
+// DefaultThemeFS returns the default theme embedded into the binary at
+// build time. It is only available when mdoc is built with -tags
+// embed; use it with ThemeFS to serve the default theme without a
+// $GOPATH checkout of mdoc's source.
+func DefaultThemeFS() (fs.FS, error) {
+	return fs.Sub(embeddedThemeFS, "contrib/themes/default")
+}
@@ -0,0 +1,120 @@
+package mdoc
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Meta holds the key/value pairs parsed from a document's front
+// matter. Title, Date, Author, and Draft provide typed access to the
+// well-known keys; arbitrary keys remain available by map lookup.
+type Meta map[string]interface{}
+
+// Title returns the "title" key, or an empty string if unset.
+func (m Meta) Title() string {
+	return m.string("title")
+}
+
+// Author returns the "author" key, or an empty string if unset.
+func (m Meta) Author() string {
+	return m.string("author")
+}
+
+// Tags returns the "tags" key split on commas and trimmed of
+// surrounding whitespace, or nil if unset. Front matter values are
+// always plain strings, so a tag list is written as a single
+// comma-separated value rather than a nested array.
+func (m Meta) Tags() []string {
+	s := m.string("tags")
+	if s == "" {
+		return nil
+	}
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// Draft reports whether the "draft" key is truthy.
+func (m Meta) Draft() bool {
+	switch v := m["draft"].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	default:
+		return false
+	}
+}
+
+// dateLayouts are the layouts tried, in order, when parsing the "date"
+// key.
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// Date returns the "date" key parsed as a time.Time, trying
+// dateLayouts in turn. It returns the zero time if unset or
+// unparseable by any of them.
+func (m Meta) Date() time.Time {
+	s := m.string("date")
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (m Meta) string(key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// frontMatterFences maps a front matter block's opening and closing
+// delimiter to the separator used between its keys and values, so
+// that both YAML-style (---) and TOML-style (+++) front matter are
+// recognized.
+var frontMatterFences = map[string]string{
+	"---": ":",
+	"+++": "=",
+}
+
+// splitFrontMatter extracts a leading front matter block from raw, if
+// present, returning the parsed key/value pairs and the remaining
+// document body. raw is returned unchanged, with a nil Meta, when no
+// recognized fence opens the file or the fence is never closed.
+func splitFrontMatter(raw []byte) (Meta, []byte) {
+	lines := bytes.SplitAfter(raw, []byte("\n"))
+	if len(lines) == 0 {
+		return nil, raw
+	}
+	fence := strings.TrimSpace(string(lines[0]))
+	sep, ok := frontMatterFences[fence]
+	if !ok {
+		return nil, raw
+	}
+	meta := make(Meta)
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(string(lines[i]))
+		if line == fence {
+			return meta, bytes.Join(lines[i+1:], nil)
+		}
+		if line == "" {
+			continue
+		}
+		if j := strings.Index(line, sep); j > 0 {
+			key := strings.TrimSpace(line[:j])
+			val := strings.Trim(strings.TrimSpace(line[j+1:]), `"'`)
+			meta[key] = val
+		}
+	}
+	return nil, raw
+}
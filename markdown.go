@@ -0,0 +1,74 @@
+package mdoc
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Heading represents a single entry in a document's table of
+// contents.
+type Heading struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+// defaultMD backs defaultMarkdown.
+var defaultMD = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(chromahtml.WithLineNumbers(false)),
+		),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// defaultMarkdown is the default value of the Markdown option. It
+// renders with goldmark's GFM extension, Chroma-highlighted fenced
+// code blocks, and autolinked heading anchors.
+func defaultMarkdown(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := defaultMD.Convert(src, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// headingPattern matches a rendered heading tag carrying an id
+// attribute, capturing its level, id, and inner HTML.
+var headingPattern = regexp.MustCompile(`(?is)<h([1-6])[^>]*\bid="([^"]*)"[^>]*>(.*?)</h[1-6]>`)
+
+// innerTagPattern strips inline markup (emphasis, code spans, links,
+// and the like) from a heading's inner HTML so Heading.Text is plain.
+var innerTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// tableOfContents scans rendered HTML for headings and returns them in
+// document order for use as DocumentPage.TOC. It operates on the
+// actual output of the configured Markdown option, rather than
+// re-parsing the source with a hard-coded goldmark instance, so it
+// works with any renderer as long as it emits an id attribute on its
+// heading tags.
+func tableOfContents(rendered []byte) []Heading {
+	var headings []Heading
+	for _, m := range headingPattern.FindAllSubmatch(rendered, -1) {
+		level, _ := strconv.Atoi(string(m[1]))
+		text := html.UnescapeString(strings.TrimSpace(innerTagPattern.ReplaceAllString(string(m[3]), "")))
+		headings = append(headings, Heading{
+			Level: level,
+			Text:  text,
+			ID:    string(m[2]),
+		})
+	}
+	return headings
+}
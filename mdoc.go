@@ -6,35 +6,52 @@ import (
 	"bytes"
 	"errors"
 	"html/template"
-	"io/ioutil"
+	"io/fs"
+	"log"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
-
-	gfm "github.com/shurcooL/github_flavored_markdown"
+	"sync"
+	"time"
 )
 
 // handler represents a http.Handler that renders Markdown documents.
 type handler struct {
 	dir              string
+	fsys             fs.FS
 	root             string
 	themeDir         string
-	assetsDir        string
+	themeFS          fs.FS
+	prebuiltDir      string
+	watchInterval    time.Duration
+	drafts           bool
+	markdown         func(src []byte) ([]byte, error)
 	indexRenderer    func(v IndexPage) ([]byte, error)
 	documentRenderer func(v DocumentPage) ([]byte, error)
+	walkRenderer     func(v WalkPage) ([]byte, error)
+	searchRenderer   func(v SearchPage) ([]byte, error)
 	errorHandler     func(w http.ResponseWriter, req *http.Request, err error)
+	searchMu         sync.RWMutex
+	searchIdx        *searchIndex
+	walkOnce         sync.Once
+	walkErr          error
+	searchOnce       sync.Once
+	searchErr        error
 }
 
-// New returns a http.Handler that renders Markdown documents.
-func New(dir string, opts ...Option) http.Handler {
-	if dir == "" {
-		dir = "."
-	}
+// newHandler builds a handler with opts applied and its renderers
+// initialized. dir is the OS directory backing fsys, or "" when fsys
+// does not live on disk (an embed.FS, for example); it is used only to
+// drive the fsnotify-based search index watcher. newHandler is shared
+// by New, NewFS, and Build so the HTTP path and the static site
+// builder render documents identically.
+func newHandler(dir string, fsys fs.FS, opts ...Option) *handler {
 	h := &handler{
 		dir:          dir,
+		fsys:         fsys,
 		root:         defaultRoot,
 		themeDir:     defaultThemeDir,
 		errorHandler: defaultErrorHandler,
@@ -42,74 +59,177 @@ func New(dir string, opts ...Option) http.Handler {
 	for _, option := range opts {
 		option(h)
 	}
-	h.indexRenderer = defaultIndexRenderer(h.themeDir)
-	h.documentRenderer = defaultDocumentRenderer(h.themeDir)
+	if h.markdown == nil {
+		h.markdown = defaultMarkdown
+	}
+	if h.themeFS == nil {
+		h.themeFS = os.DirFS(h.themeDir)
+	}
+	h.indexRenderer = defaultIndexRenderer(h.themeFS)
+	h.documentRenderer = defaultDocumentRenderer(h.themeFS)
+	return h
+}
+
+// getWalkRenderer returns h.walkRenderer, building it from the theme
+// on first use if no WalkRenderer option supplied one. Building it
+// lazily, rather than unconditionally in newHandler, means a theme
+// that never defines walk.html only fails when /.mdoc/walk is actually
+// requested, not for every handler that happens to use that theme.
+func (h *handler) getWalkRenderer() (func(WalkPage) ([]byte, error), error) {
+	if h.walkRenderer != nil {
+		return h.walkRenderer, nil
+	}
+	h.walkOnce.Do(func() {
+		h.walkRenderer, h.walkErr = defaultWalkRenderer(h.themeFS)
+	})
+	return h.walkRenderer, h.walkErr
+}
+
+// getSearchRenderer returns h.searchRenderer, building it from the
+// theme on first use if no SearchRenderer option supplied one. See
+// getWalkRenderer for why this is lazy rather than built in newHandler.
+func (h *handler) getSearchRenderer() (func(SearchPage) ([]byte, error), error) {
+	if h.searchRenderer != nil {
+		return h.searchRenderer, nil
+	}
+	h.searchOnce.Do(func() {
+		h.searchRenderer, h.searchErr = defaultSearchRenderer(h.themeFS)
+	})
+	return h.searchRenderer, h.searchErr
+}
+
+// New returns a http.Handler that renders the Markdown documents
+// found under dir on disk.
+func New(dir string, opts ...Option) http.Handler {
+	if dir == "" {
+		dir = "."
+	}
+	return newFS(dir, os.DirFS(dir), opts...)
+}
+
+// NewFS returns a http.Handler that renders the Markdown documents
+// found in fsys, for example a directory embedded into the binary
+// with //go:embed.
+func NewFS(fsys fs.FS, opts ...Option) http.Handler {
+	return newFS("", fsys, opts...)
+}
+
+func newFS(dir string, fsys fs.FS, opts ...Option) http.Handler {
+	h := newHandler(dir, fsys, opts...)
 	m := http.NewServeMux()
 	m.Handle("/.mdoc/assets/", http.StripPrefix("/.mdoc/assets/", h.assets()))
+	m.Handle("/.mdoc/walk/", h.walks())
+	m.Handle("/.mdoc/search", h.search())
 	m.Handle("/", h)
+	h.rebuildSearchIndex()
+	go h.watchSearch()
+	if h.prebuiltDir != "" {
+		go h.watch()
+	}
 	return m
 }
 
+// watch rebuilds the prebuilt HTML tree at h.prebuiltDir on a polling
+// interval, defaulting to defaultWatchInterval, so that ServeHTTP can
+// serve the static output in place while the server keeps running.
+func (h *handler) watch() {
+	interval := h.watchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	for {
+		if err := h.buildTree(h.prebuiltDir); err != nil {
+			log.Printf("mdoc: watch: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
 // ServeHTTP implements the http.Handler interface.
 func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	url := req.URL.Path
 	if !strings.HasPrefix(url, "/") {
 		url = "/" + url
 	}
-	name := h.dir
-	if url != "/" {
-		name += path.Clean(url)
+	if h.prebuiltDir != "" {
+		name := h.prebuiltPath(url)
+		if fi, err := os.Stat(name); err == nil && !fi.IsDir() {
+			http.ServeFile(w, req, name)
+			return
+		}
 	}
-	f, err := os.Open(name)
-	if err != nil {
-		h.errorHandler(w, req, err)
-		return
+	name := "."
+	if url != "/" {
+		name = strings.TrimPrefix(path.Clean(url), "/")
 	}
-	defer f.Close()
-	fi, err := f.Stat()
+	fi, err := fs.Stat(h.fsys, name)
 	if err != nil {
 		h.errorHandler(w, req, err)
 		return
 	}
 	isIndexPage := false
+	dirPath := name
 	if fi.IsDir() {
 		if !strings.HasSuffix(url, "/") {
 			redirect(w, req, path.Base(url)+"/")
 			return
 		}
-		name += "/index.md"
-		ff, err := os.Open(name)
-		if err == nil {
-			defer ff.Close()
-			ffi, err := ff.Stat()
-			if err == nil {
-				f = ff
-				fi = ffi
-				isIndexPage = true
-			}
+		indexName := path.Join(name, "index.md")
+		if ifi, err := fs.Stat(h.fsys, indexName); err == nil {
+			name = indexName
+			fi = ifi
+			isIndexPage = true
 		}
 	}
 	var b []byte
 	if fi.IsDir() {
-		b, err = h.renderIndex(w, f, url)
+		files, err := getFiles(h.fsys, dirPath, h.drafts)
+		if err != nil {
+			h.errorHandler(w, req, err)
+			return
+		}
+		b, err = h.renderIndex(files, url)
 		if err != nil {
 			h.errorHandler(w, req, err)
+			return
 		}
 	} else if !isIndexPage && strings.HasSuffix(url, "/") {
 		redirect(w, req, "../"+path.Base(url))
 		return
 	} else {
-		b, err = h.renderDocument(w, f, url)
+		raw, err := fs.ReadFile(h.fsys, name)
 		if err != nil {
 			h.errorHandler(w, req, err)
+			return
+		}
+		b, err = h.renderDocument(path.Base(name), raw, url)
+		if err != nil {
+			h.errorHandler(w, req, err)
+			return
 		}
 	}
 	http.ServeContent(w, req, name, fi.ModTime(), bytes.NewReader(b))
 }
 
 func (h *handler) assets() http.Handler {
-	h.assetsDir = filepath.Join(h.themeDir, "assets")
-	return http.FileServer(http.Dir(h.assetsDir))
+	sub, err := fs.Sub(h.themeFS, "assets")
+	if err != nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h.errorHandler(w, req, err)
+		})
+	}
+	return http.FileServer(http.FS(sub))
+}
+
+// prebuiltPath returns the path under h.prebuiltDir that Build would
+// have written for url, mirroring the pretty-URL layout that
+// buildDocument and buildDir produce.
+func (h *handler) prebuiltPath(url string) string {
+	p := url
+	if !strings.HasSuffix(p, "/") && isMarkdownFile(p) {
+		p = strings.TrimSuffix(p, path.Ext(p))
+	}
+	return filepath.Join(h.prebuiltDir, filepath.FromSlash(p), "index.html")
 }
 
 // Layout represents the page data used by both
@@ -136,15 +256,17 @@ type IndexPage struct {
 	Files []File
 }
 
-func (h *handler) renderIndex(w http.ResponseWriter, f *os.File, path string) ([]byte, error) {
-	files, err := getFiles(f)
-	if err != nil {
-		return nil, err
-	}
+// WalkURL returns the URL of the codewalk declared by f, for use by
+// themes rendering an IndexPage. Only meaningful when f.IsWalk.
+func (v IndexPage) WalkURL(f File) string {
+	return path.Join(v.root, ".mdoc/walk", walkName(f.Name))
+}
+
+func (h *handler) renderIndex(files []File, urlPath string) ([]byte, error) {
 	v := IndexPage{
 		Layout: Layout{
 			root: h.root,
-			path: path,
+			path: urlPath,
 		},
 		Files: files,
 	}
@@ -155,29 +277,61 @@ func (h *handler) renderIndex(w http.ResponseWriter, f *os.File, path string) ([
 type DocumentPage struct {
 	Layout
 	Name    string
+	Meta    Meta
+	TOC     []Heading
 	Content template.HTML
 }
 
+// Title returns the document's front matter title, if any.
+func (v DocumentPage) Title() string {
+	return v.Meta.Title()
+}
+
+// Author returns the document's front matter author, if any.
+func (v DocumentPage) Author() string {
+	return v.Meta.Author()
+}
+
+// Date returns the document's front matter date, if any.
+func (v DocumentPage) Date() time.Time {
+	return v.Meta.Date()
+}
+
+// IsDraft reports whether the document's front matter marks it a draft.
+func (v DocumentPage) IsDraft() bool {
+	return v.Meta.Draft()
+}
+
+// Tags returns the document's front matter tags, if any.
+func (v DocumentPage) Tags() []string {
+	return v.Meta.Tags()
+}
+
 // ErrNotFound represents that the file does not exist or
 // is not a Markdown file.
 var ErrNotFound = errors.New("mdoc: file not found")
 
-func (h *handler) renderDocument(w http.ResponseWriter, f *os.File, path string) ([]byte, error) {
-	name := f.Name()
-	if !isMarkdownFile(name) {
+func (h *handler) renderDocument(name string, raw []byte, urlPath string) ([]byte, error) {
+	if !isMarkdownFile(name) || isWalkFile(name) {
 		return nil, ErrNotFound
 	}
-	raw, err := ioutil.ReadAll(f)
+	meta, body := splitFrontMatter(raw)
+	if meta.Draft() && !h.drafts {
+		return nil, ErrNotFound
+	}
+	rendered, err := h.markdown(body)
 	if err != nil {
 		return nil, err
 	}
 	v := DocumentPage{
 		Layout: Layout{
 			root: h.root,
-			path: path,
+			path: urlPath,
 		},
 		Name:    name,
-		Content: template.HTML(string(gfm.Markdown(raw))),
+		Meta:    meta,
+		TOC:     tableOfContents(rendered),
+		Content: template.HTML(string(rendered)),
 	}
 	return h.documentRenderer(v)
 }
@@ -201,27 +355,49 @@ func isMarkdownFile(name string) bool {
 	return false
 }
 
-func getFiles(f *os.File) ([]File, error) {
-	fis, err := f.Readdir(-1)
+func getFiles(fsys fs.FS, dirPath string, drafts bool) ([]File, error) {
+	entries, err := fs.ReadDir(fsys, dirPath)
 	if err != nil {
 		return nil, err
 	}
 	var files []File
-	for _, fi := range fis {
+	for _, fi := range entries {
 		f := File{Name: fi.Name(), IsDir: fi.IsDir()}
-		if strings.HasPrefix(f.Name, ".") || (!f.IsDir && !isMarkdownFile(f.Name)) {
+		isWalk := !f.IsDir && isWalkFile(f.Name)
+		if strings.HasPrefix(f.Name, ".") || (!f.IsDir && !isMarkdownFile(f.Name) && !isWalk) {
 			continue
 		}
+		if !f.IsDir {
+			raw, err := fs.ReadFile(fsys, path.Join(dirPath, f.Name))
+			if err == nil {
+				meta, _ := splitFrontMatter(raw)
+				if isWalk {
+					f.IsWalk = true
+					f.Title = meta.Title()
+				} else {
+					if meta.Draft() && !drafts {
+						continue
+					}
+					f.Title = meta.Title()
+					f.Date = meta.Date()
+					f.Tags = meta.Tags()
+				}
+			}
+		}
 		files = append(files, f)
 	}
-	sort.Sort(byName(files))
+	sort.Sort(byDate(files))
 	return files, nil
 }
 
 // File represents a file for use in a HTML view.
 type File struct {
-	Name  string
-	IsDir bool
+	Name   string
+	IsDir  bool
+	IsWalk bool
+	Title  string
+	Date   time.Time
+	Tags   []string
 }
 
 // DisplayName returns the file name with a forward
@@ -233,17 +409,21 @@ func (f File) DisplayName() string {
 	return f.Name
 }
 
-type byName []File
+// byDate orders directories first, then documents newest-date-first,
+// falling back to the name when dates tie or are both zero.
+type byDate []File
 
-func (v byName) Len() int      { return len(v) }
-func (v byName) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v byDate) Len() int      { return len(v) }
+func (v byDate) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
 
-func (v byName) Less(i, j int) bool {
+func (v byDate) Less(i, j int) bool {
 	switch {
 	case v[i].IsDir && !v[j].IsDir:
 		return true
 	case !v[i].IsDir && v[j].IsDir:
 		return false
+	case !v[i].Date.Equal(v[j].Date):
+		return v[i].Date.After(v[j].Date)
 	default:
 		return v[i].Name < v[j].Name
 	}
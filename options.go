@@ -3,9 +3,11 @@ package mdoc
 import (
 	"bytes"
 	"html/template"
+	"io/fs"
 	"net/http"
 	"os"
-	"path/filepath"
+	"sort"
+	"time"
 )
 
 // Option describes a functional option for configuring the handler.
@@ -33,6 +35,26 @@ func Theme(dir string) Option {
 // defaultThemeDir is the relative path to the default theme.
 const defaultThemeDir = "contrib/themes/default"
 
+// ThemeFS sets the filesystem templates and assets are loaded from,
+// taking precedence over Theme. Use this to serve a theme embedded in
+// the binary with //go:embed, for example.
+func ThemeFS(fsys fs.FS) Option {
+	return func(h *handler) {
+		h.themeFS = fsys
+	}
+}
+
+// Markdown sets the function used to render a document's body to
+// HTML. Defaults to defaultMarkdown, backed by goldmark with GitHub
+// Flavored Markdown, Chroma-highlighted fenced code blocks, and
+// autolinked heading anchors. Supply your own to render a different
+// Markdown dialect.
+func Markdown(fn func(src []byte) ([]byte, error)) Option {
+	return func(h *handler) {
+		h.markdown = fn
+	}
+}
+
 // IndexRenderer sets the IndexPage rendering function.
 // Defaults to a basic rendering function.
 func IndexRenderer(fn func(v IndexPage) ([]byte, error)) Option {
@@ -41,10 +63,11 @@ func IndexRenderer(fn func(v IndexPage) ([]byte, error)) Option {
 	}
 }
 
-// defaultIndexRenderer returns a default IndexPage renderer.
-func defaultIndexRenderer(themeDir string) func(IndexPage) ([]byte, error) {
-	t := template.Must(template.ParseFiles(filepath.Join(themeDir, "layout.html")))
-	t = template.Must(t.ParseFiles(filepath.Join(themeDir, "index.html")))
+// defaultIndexRenderer returns a default IndexPage renderer. Files is
+// already sorted by date, and the template has access to byTag and
+// tags so a theme can also group entries by their front matter tags.
+func defaultIndexRenderer(themeFS fs.FS) func(IndexPage) ([]byte, error) {
+	t := template.Must(template.New("layout.html").Funcs(indexFuncs).ParseFS(themeFS, "layout.html", "index.html"))
 	return func(v IndexPage) ([]byte, error) {
 		var buf bytes.Buffer
 		err := t.Execute(&buf, v)
@@ -55,6 +78,38 @@ func defaultIndexRenderer(themeDir string) func(IndexPage) ([]byte, error) {
 	}
 }
 
+// indexFuncs are the template functions available to index.html, for
+// themes that want to group File.Tags beyond the date-ordering
+// defaultIndexRenderer already applies.
+var indexFuncs = template.FuncMap{
+	"byTag": func(tag string, files []File) []File {
+		var out []File
+		for _, f := range files {
+			for _, t := range f.Tags {
+				if t == tag {
+					out = append(out, f)
+					break
+				}
+			}
+		}
+		return out
+	},
+	"tags": func(files []File) []string {
+		seen := make(map[string]bool)
+		var out []string
+		for _, f := range files {
+			for _, t := range f.Tags {
+				if !seen[t] {
+					seen[t] = true
+					out = append(out, t)
+				}
+			}
+		}
+		sort.Strings(out)
+		return out
+	},
+}
+
 // DocumentRenderer sets the DocumentPage rendering function.
 // Defaults to a basic rendering function.
 func DocumentRenderer(fn func(v DocumentPage) ([]byte, error)) Option {
@@ -64,9 +119,8 @@ func DocumentRenderer(fn func(v DocumentPage) ([]byte, error)) Option {
 }
 
 // defaultDocumentRenderer returns a the default DocumentPage renderer.
-func defaultDocumentRenderer(themeDir string) func(DocumentPage) ([]byte, error) {
-	t := template.Must(template.ParseFiles(filepath.Join(themeDir, "layout.html")))
-	t = template.Must(t.ParseFiles(filepath.Join(themeDir, "doc.html")))
+func defaultDocumentRenderer(themeFS fs.FS) func(DocumentPage) ([]byte, error) {
+	t := template.Must(template.ParseFS(themeFS, "layout.html", "doc.html"))
 	return func(v DocumentPage) ([]byte, error) {
 		var buf bytes.Buffer
 		err := t.Execute(&buf, v)
@@ -77,6 +131,86 @@ func defaultDocumentRenderer(themeDir string) func(DocumentPage) ([]byte, error)
 	}
 }
 
+// WalkRenderer sets the WalkPage rendering function.
+// Defaults to a basic rendering function.
+func WalkRenderer(fn func(v WalkPage) ([]byte, error)) Option {
+	return func(h *handler) {
+		h.walkRenderer = fn
+	}
+}
+
+// defaultWalkRenderer returns a default WalkPage renderer, or an error
+// if themeFS does not provide walk.html. Unlike defaultIndexRenderer
+// and defaultDocumentRenderer, this is built lazily on first use: a
+// theme predating codewalks need not define walk.html unless /.mdoc/walk
+// is actually requested.
+func defaultWalkRenderer(themeFS fs.FS) (func(WalkPage) ([]byte, error), error) {
+	t, err := template.New("layout.html").ParseFS(themeFS, "layout.html", "walk.html")
+	if err != nil {
+		return nil, err
+	}
+	return func(v WalkPage) ([]byte, error) {
+		var buf bytes.Buffer
+		err := t.Execute(&buf, v)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}, nil
+}
+
+// SearchRenderer sets the SearchPage rendering function.
+// Defaults to a basic rendering function.
+func SearchRenderer(fn func(v SearchPage) ([]byte, error)) Option {
+	return func(h *handler) {
+		h.searchRenderer = fn
+	}
+}
+
+// defaultSearchRenderer returns a default SearchPage renderer, or an
+// error if themeFS does not provide search.html. Built lazily on first
+// use for the same reason as defaultWalkRenderer: a theme need not
+// define search.html unless /.mdoc/search is actually requested.
+func defaultSearchRenderer(themeFS fs.FS) (func(SearchPage) ([]byte, error), error) {
+	t, err := template.New("layout.html").ParseFS(themeFS, "layout.html", "search.html")
+	if err != nil {
+		return nil, err
+	}
+	return func(v SearchPage) ([]byte, error) {
+		var buf bytes.Buffer
+		err := t.Execute(&buf, v)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}, nil
+}
+
+// Watch enables a background poller that rebuilds the static HTML
+// tree rooted at outDir every interval, à la Caddy's markdown
+// middleware, while the handler keeps serving live. ServeHTTP prefers
+// files from outDir when present, falling back to live rendering of
+// dir otherwise. interval defaults to defaultWatchInterval when <= 0.
+func Watch(outDir string, interval time.Duration) Option {
+	return func(h *handler) {
+		h.prebuiltDir = outDir
+		h.watchInterval = interval
+	}
+}
+
+// defaultWatchInterval is the polling interval used by Watch when
+// interval is <= 0.
+const defaultWatchInterval = 4 * time.Second
+
+// Drafts controls whether documents and index entries whose front
+// matter sets draft: true are rendered. Defaults to false, hiding
+// drafts from both directory listings and direct requests.
+func Drafts(drafts bool) Option {
+	return func(h *handler) {
+		h.drafts = drafts
+	}
+}
+
 // ErrorHandler sets the http.Handler to delegate to when errors are returned.
 // Defaults to writing a response with HTTP 404 Not Found if the package fails
 // to import, otherwise HTTP 500 Internal Server Error to the response.
@@ -0,0 +1,464 @@
+package mdoc
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// bm25K1 and bm25B are the standard Okapi BM25 tuning constants.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopWords are excluded from the inverted index and from query
+// terms; they carry no discriminating weight for retrieval.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// posting records a term's occurrences within a single document.
+type posting struct {
+	doc int
+	pos []int
+}
+
+// searchDoc is one indexed document.
+type searchDoc struct {
+	path   string
+	title  string
+	words  []string
+	length int
+}
+
+// searchIndex is an in-memory inverted index over h.fsys's Markdown
+// documents, used to answer /.mdoc/search queries.
+type searchIndex struct {
+	docs     []searchDoc
+	postings map[string][]posting
+	avgLen   float64
+}
+
+// buildSearchIndex walks fsys, tokenizing and indexing every Markdown
+// document that is not a codewalk declaration.
+func buildSearchIndex(fsys fs.FS, drafts bool) (*searchIndex, error) {
+	idx := &searchIndex{postings: make(map[string][]posting)}
+	var totalLen int
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdownFile(p) || isWalkFile(p) {
+			return nil
+		}
+		raw, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		meta, body := splitFrontMatter(raw)
+		if meta.Draft() && !drafts {
+			return nil
+		}
+		title := meta.Title()
+		if title == "" {
+			title = path.Base(p)
+		}
+		words := tokenize(string(body))
+		docID := len(idx.docs)
+		idx.docs = append(idx.docs, searchDoc{
+			path:   "/" + p,
+			title:  title,
+			words:  words,
+			length: len(words),
+		})
+		totalLen += len(words)
+		positions := make(map[string][]int)
+		for i, word := range words {
+			if stopWords[word] {
+				continue
+			}
+			term := stem(word)
+			positions[term] = append(positions[term], i)
+		}
+		for term, pos := range positions {
+			idx.postings[term] = append(idx.postings[term], posting{doc: docID, pos: pos})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(idx.docs) > 0 {
+		idx.avgLen = float64(totalLen) / float64(len(idx.docs))
+	}
+	return idx, nil
+}
+
+// tokenize splits text on runs of non-letter, non-digit runes and
+// lower-cases the result, preserving position so that phrase queries
+// can require adjacency.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// queryTerm is a single unit of a parsed search query: a stemmed word,
+// a stemmed phrase, or an unstemmed prefix.
+type queryTerm struct {
+	words  []string
+	prefix bool
+}
+
+// parseQuery splits a query into terms, recognizing quoted phrases
+// and a trailing "*" for prefix matching. Bare terms are combined
+// with boolean AND.
+func parseQuery(q string) []queryTerm {
+	var terms []queryTerm
+	r := []rune(q)
+	i := 0
+	for i < len(r) {
+		for i < len(r) && unicode.IsSpace(r[i]) {
+			i++
+		}
+		if i >= len(r) {
+			break
+		}
+		if r[i] == '"' {
+			j := i + 1
+			for j < len(r) && r[j] != '"' {
+				j++
+			}
+			words := tokenize(string(r[i+1 : j]))
+			if len(words) > 0 {
+				stemmed := make([]string, len(words))
+				for k, w := range words {
+					stemmed[k] = stem(w)
+				}
+				terms = append(terms, queryTerm{words: stemmed})
+			}
+			if j < len(r) {
+				j++
+			}
+			i = j
+			continue
+		}
+		j := i
+		for j < len(r) && !unicode.IsSpace(r[j]) {
+			j++
+		}
+		word := strings.ToLower(string(r[i:j]))
+		i = j
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.TrimSuffix(word, "*")
+		if word == "" {
+			continue
+		}
+		if prefix {
+			terms = append(terms, queryTerm{words: []string{word}, prefix: true})
+		} else if !stopWords[word] {
+			terms = append(terms, queryTerm{words: []string{stem(word)}})
+		}
+	}
+	return terms
+}
+
+// match returns the matching positions per document ID for a single
+// query term: a union of postings for a prefix, the postings for a
+// single term, or adjacency-filtered positions for a phrase.
+func (idx *searchIndex) match(qt queryTerm) map[int][]int {
+	result := make(map[int][]int)
+	switch {
+	case qt.prefix:
+		for term, postings := range idx.postings {
+			if !strings.HasPrefix(term, qt.words[0]) {
+				continue
+			}
+			for _, p := range postings {
+				result[p.doc] = append(result[p.doc], p.pos...)
+			}
+		}
+	case len(qt.words) == 1:
+		for _, p := range idx.postings[qt.words[0]] {
+			result[p.doc] = p.pos
+		}
+	default:
+		byDoc := make(map[int][]int)
+		for _, p := range idx.postings[qt.words[0]] {
+			byDoc[p.doc] = p.pos
+		}
+		for _, word := range qt.words[1:] {
+			next := make(map[int][]int)
+			for _, p := range idx.postings[word] {
+				prev, ok := byDoc[p.doc]
+				if !ok {
+					continue
+				}
+				prevSet := make(map[int]bool, len(prev))
+				for _, pp := range prev {
+					prevSet[pp] = true
+				}
+				var matched []int
+				for _, pos := range p.pos {
+					if prevSet[pos-1] {
+						matched = append(matched, pos)
+					}
+				}
+				if len(matched) > 0 {
+					next[p.doc] = matched
+				}
+			}
+			byDoc = next
+		}
+		result = byDoc
+	}
+	return result
+}
+
+// bm25 scores a single term's contribution to a document using Okapi
+// BM25 (k1=1.2, b=0.75).
+func bm25(tf, df, n, docLen, avgLen float64) float64 {
+	if df == 0 || n == 0 || avgLen == 0 {
+		return 0
+	}
+	idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+	norm := 1 - bm25B + bm25B*docLen/avgLen
+	return idf * (tf * (bm25K1 + 1)) / (tf + bm25K1*norm)
+}
+
+// Hit represents a single scored search result.
+type Hit struct {
+	Path    string        `json:"path"`
+	Title   string        `json:"title"`
+	Snippet template.HTML `json:"snippet"`
+	Score   float64       `json:"score"`
+}
+
+// search answers q against idx, combining terms with boolean AND and
+// ranking matches by summed BM25 score, highest first. A nil idx
+// (the index failed to build) answers with zero hits rather than
+// panicking.
+func (idx *searchIndex) search(q string) []Hit {
+	if idx == nil {
+		return nil
+	}
+	terms := parseQuery(q)
+	if len(terms) == 0 {
+		return nil
+	}
+	matches := make([]map[int][]int, len(terms))
+	var common map[int]bool
+	for i, qt := range terms {
+		matches[i] = idx.match(qt)
+		ids := make(map[int]bool, len(matches[i]))
+		for id := range matches[i] {
+			ids[id] = true
+		}
+		if i == 0 {
+			common = ids
+			continue
+		}
+		for id := range common {
+			if !ids[id] {
+				delete(common, id)
+			}
+		}
+	}
+	n := float64(len(idx.docs))
+	hits := make([]Hit, 0, len(common))
+	for docID := range common {
+		doc := idx.docs[docID]
+		var score float64
+		var positions []int
+		for i := range terms {
+			pos := matches[i][docID]
+			df := float64(len(matches[i]))
+			score += bm25(float64(len(pos)), df, n, float64(doc.length), idx.avgLen)
+			positions = append(positions, pos...)
+		}
+		hits = append(hits, Hit{
+			Path:    doc.path,
+			Title:   doc.title,
+			Snippet: snippet(doc, positions),
+			Score:   score,
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits
+}
+
+// snippetWindow is the number of words shown on either side of a
+// matched term in a search result snippet.
+const snippetWindow = 8
+
+// snippet renders a short excerpt of doc around its first matched
+// position, wrapping every matched word in <mark>.
+func snippet(doc searchDoc, positions []int) template.HTML {
+	if len(positions) == 0 || len(doc.words) == 0 {
+		n := len(doc.words)
+		if n > snippetWindow*2 {
+			n = snippetWindow * 2
+		}
+		return template.HTML(template.HTMLEscapeString(strings.Join(doc.words[:n], " ")))
+	}
+	sort.Ints(positions)
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+	start := positions[0] - snippetWindow
+	if start < 0 {
+		start = 0
+	}
+	end := positions[0] + snippetWindow + 1
+	if end > len(doc.words) {
+		end = len(doc.words)
+	}
+	var b strings.Builder
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		w := template.HTMLEscapeString(doc.words[i])
+		if matched[i] {
+			b.WriteString("<mark>")
+			b.WriteString(w)
+			b.WriteString("</mark>")
+		} else {
+			b.WriteString(w)
+		}
+	}
+	return template.HTML(b.String())
+}
+
+// SearchPage represents the data used to render a search results page.
+type SearchPage struct {
+	Layout
+	Query string
+	Hits  []Hit
+}
+
+// rebuildSearchIndex walks h.fsys and swaps in a freshly built index.
+func (h *handler) rebuildSearchIndex() {
+	idx, err := buildSearchIndex(h.fsys, h.drafts)
+	if err != nil {
+		log.Printf("mdoc: search: rebuild: %v", err)
+		return
+	}
+	h.searchMu.Lock()
+	h.searchIdx = idx
+	h.searchMu.Unlock()
+}
+
+func (h *handler) searchIndexSnapshot() *searchIndex {
+	h.searchMu.RLock()
+	defer h.searchMu.RUnlock()
+	return h.searchIdx
+}
+
+// watchSearch rebuilds the search index whenever a file under h.dir
+// changes, keeping results current without a server restart. It does
+// nothing when h.dir is empty, since fsys then isn't backed by a real
+// directory fsnotify can watch (an embed.FS, for example).
+func (h *handler) watchSearch() {
+	if h.dir == "" {
+		return
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("mdoc: search: %v", err)
+		return
+	}
+	defer w.Close()
+	err = filepath.Walk(h.dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return w.Add(p)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("mdoc: search: %v", err)
+		return
+	}
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					w.Add(event.Name)
+				}
+			}
+			h.rebuildSearchIndex()
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("mdoc: search: %v", err)
+		}
+	}
+}
+
+func (h *handler) search() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query().Get("q")
+		hits := h.searchIndexSnapshot().search(q)
+		if req.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(hits); err != nil {
+				h.errorHandler(w, req, err)
+			}
+			return
+		}
+		v := SearchPage{
+			Layout: Layout{
+				root: h.root,
+				path: req.URL.Path,
+			},
+			Query: q,
+			Hits:  hits,
+		}
+		render, err := h.getSearchRenderer()
+		if err != nil {
+			h.errorHandler(w, req, err)
+			return
+		}
+		b, err := render(v)
+		if err != nil {
+			h.errorHandler(w, req, err)
+			return
+		}
+		w.Write(b)
+	})
+}
@@ -0,0 +1,134 @@
+package mdoc
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		q     string
+		words [][]string
+		pfx   []bool
+	}{
+		{`fox`, [][]string{{stem("fox")}}, []bool{false}},
+		{`quick fox`, [][]string{{stem("quick")}, {stem("fox")}}, []bool{false, false}},
+		{`"quick fox"`, [][]string{{stem("quick"), stem("fox")}}, []bool{false}},
+		{`fo*`, [][]string{{"fo"}}, []bool{true}},
+	}
+	for _, c := range cases {
+		terms := parseQuery(c.q)
+		if len(terms) != len(c.words) {
+			t.Fatalf("parseQuery(%q) = %d terms, want %d: %+v", c.q, len(terms), len(c.words), terms)
+		}
+		for i, term := range terms {
+			if term.prefix != c.pfx[i] {
+				t.Errorf("parseQuery(%q) term %d prefix = %v, want %v", c.q, i, term.prefix, c.pfx[i])
+			}
+			if len(term.words) != len(c.words[i]) {
+				t.Fatalf("parseQuery(%q) term %d words = %v, want %v", c.q, i, term.words, c.words[i])
+			}
+			for j, w := range term.words {
+				if w != c.words[i][j] {
+					t.Errorf("parseQuery(%q) term %d word %d = %q, want %q", c.q, i, j, w, c.words[i][j])
+				}
+			}
+		}
+	}
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"a.md": {Data: []byte("---\ntitle: A\n---\nthe quick brown fox jumps\n")},
+		"b.md": {Data: []byte("---\ntitle: B\n---\na fox without the other word nearby\n")},
+	}
+}
+
+// TestSearchPhraseRequiresAdjacency ensures a quoted phrase only
+// matches when its stemmed words appear consecutively, not merely
+// present in the same document.
+func TestSearchPhraseRequiresAdjacency(t *testing.T) {
+	idx, err := buildSearchIndex(testFS(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "quick" and "fox" both occur in a.md ("the quick brown fox
+	// jumps") but with "brown" between them, so the phrase must not
+	// match.
+	hits := idx.search(`"quick fox"`)
+	if len(hits) != 0 {
+		t.Fatalf(`search("quick fox") = %+v, want no hits`, hits)
+	}
+	hits = idx.search(`"quick brown"`)
+	if len(hits) != 1 || hits[0].Path != "/a.md" {
+		t.Fatalf(`search("quick brown") = %+v, want exactly /a.md`, hits)
+	}
+	hits = idx.search(`"fox jumps"`)
+	if len(hits) != 1 || hits[0].Path != "/a.md" {
+		t.Fatalf(`search("fox jumps") = %+v, want exactly /a.md`, hits)
+	}
+	// "fox" and "word" both occur in b.md but never adjacently.
+	hits = idx.search(`"fox word"`)
+	if len(hits) != 0 {
+		t.Fatalf(`search("fox word") = %+v, want no hits`, hits)
+	}
+}
+
+// TestSearchANDRequiresAllTerms ensures bare terms are combined with
+// boolean AND, not OR.
+func TestSearchANDRequiresAllTerms(t *testing.T) {
+	idx, err := buildSearchIndex(testFS(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := idx.search("fox")
+	if len(hits) != 2 {
+		t.Fatalf(`search("fox") = %d hits, want 2`, len(hits))
+	}
+	hits = idx.search("quick fox")
+	if len(hits) != 1 || hits[0].Path != "/a.md" {
+		t.Fatalf(`search("quick fox") = %+v, want exactly /a.md`, hits)
+	}
+}
+
+// TestSearchPrefixMatchesWholeTerm ensures a trailing "*" matches any
+// term sharing that prefix.
+func TestSearchPrefixMatchesWholeTerm(t *testing.T) {
+	idx, err := buildSearchIndex(testFS(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := idx.search("jum*")
+	if len(hits) != 1 || hits[0].Path != "/a.md" {
+		t.Fatalf(`search("jum*") = %+v, want exactly /a.md`, hits)
+	}
+}
+
+// TestBM25RewardsTermFrequency checks that, all else equal, a document
+// repeating the query term scores higher than one mentioning it once.
+func TestBM25RewardsTermFrequency(t *testing.T) {
+	fsys := fstest.MapFS{
+		"once.md":  {Data: []byte("---\ntitle: Once\n---\napple banana cherry date\n")},
+		"twice.md": {Data: []byte("---\ntitle: Twice\n---\napple apple banana cherry\n")},
+	}
+	idx, err := buildSearchIndex(fsys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := idx.search("apple")
+	if len(hits) != 2 {
+		t.Fatalf(`search("apple") = %d hits, want 2`, len(hits))
+	}
+	if hits[0].Path != "/twice.md" {
+		t.Fatalf("expected /twice.md to rank first, got %+v", hits)
+	}
+}
+
+// TestNilSearchIndexReturnsNoHits guards against a panic when the
+// index has never successfully built.
+func TestNilSearchIndexReturnsNoHits(t *testing.T) {
+	var idx *searchIndex
+	if hits := idx.search("anything"); hits != nil {
+		t.Fatalf("search on a nil index = %v, want nil", hits)
+	}
+}
@@ -0,0 +1,231 @@
+package mdoc
+
+import "strings"
+
+// stem reduces word to its Porter stem (M.F. Porter, "An algorithm
+// for suffix stripping", 1980), so that postings for "routine",
+// "routines", and "routining" share a single index term.
+func stem(word string) string {
+	w := []rune(strings.ToLower(word))
+	if len(w) <= 2 {
+		return string(w)
+	}
+	w = step1a(w)
+	w = step1b(w)
+	w = step1c(w)
+	w = step2(w)
+	w = step3(w)
+	w = step4(w)
+	w = step5a(w)
+	w = step5b(w)
+	return string(w)
+}
+
+// isConsonant reports whether the rune at i is a consonant, treating
+// "y" as a consonant only when it does not follow another consonant.
+func isConsonant(w []rune, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		if i == 0 {
+			return true
+		}
+		return !isConsonant(w, i-1)
+	default:
+		return true
+	}
+}
+
+// measure counts the consonant-vowel sequences in w, the Porter
+// algorithm's "m".
+func measure(w []rune) int {
+	n, i := 0, 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func containsVowel(w []rune) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []rune) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+func endsCVC(w []rune) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func hasSuffix(w []rune, suf string) bool {
+	return len(w) >= len(suf) && string(w[len(w)-len(suf):]) == suf
+}
+
+func trimSuffix(w []rune, n int) []rune {
+	return w[:len(w)-n]
+}
+
+func step1a(w []rune) []rune {
+	switch {
+	case hasSuffix(w, "sses"):
+		return append(trimSuffix(w, 2))
+	case hasSuffix(w, "ies"):
+		return append(trimSuffix(w, 3), 'i')
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return trimSuffix(w, 1)
+	}
+	return w
+}
+
+func step1b(w []rune) []rune {
+	switch {
+	case hasSuffix(w, "eed"):
+		if measure(trimSuffix(w, 3)) > 0 {
+			return trimSuffix(w, 1)
+		}
+		return w
+	case hasSuffix(w, "ed"):
+		s := trimSuffix(w, 2)
+		if containsVowel(s) {
+			return step1bPost(s)
+		}
+		return w
+	case hasSuffix(w, "ing"):
+		s := trimSuffix(w, 3)
+		if containsVowel(s) {
+			return step1bPost(s)
+		}
+		return w
+	}
+	return w
+}
+
+func step1bPost(w []rune) []rune {
+	switch {
+	case hasSuffix(w, "at"), hasSuffix(w, "bl"), hasSuffix(w, "iz"):
+		return append(w, 'e')
+	case endsDoubleConsonant(w) && !hasSuffix(w, "l") && !hasSuffix(w, "s") && !hasSuffix(w, "z"):
+		return trimSuffix(w, 1)
+	case measure(w) == 1 && endsCVC(w):
+		return append(w, 'e')
+	}
+	return w
+}
+
+func step1c(w []rune) []rune {
+	if hasSuffix(w, "y") && containsVowel(trimSuffix(w, 1)) {
+		return append(trimSuffix(w, 1), 'i')
+	}
+	return w
+}
+
+var step2Suffixes = []struct{ suf, repl string }{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func step2(w []rune) []rune {
+	for _, r := range step2Suffixes {
+		if hasSuffix(w, r.suf) {
+			s := trimSuffix(w, len(r.suf))
+			if measure(s) > 0 {
+				return append(s, []rune(r.repl)...)
+			}
+			return w
+		}
+	}
+	return w
+}
+
+var step3Suffixes = []struct{ suf, repl string }{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func step3(w []rune) []rune {
+	for _, r := range step3Suffixes {
+		if hasSuffix(w, r.suf) {
+			s := trimSuffix(w, len(r.suf))
+			if measure(s) > 0 {
+				return append(s, []rune(r.repl)...)
+			}
+			return w
+		}
+	}
+	return w
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+	"ment", "ent", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(w []rune) []rune {
+	for _, suf := range step4Suffixes {
+		if hasSuffix(w, suf) {
+			s := trimSuffix(w, len(suf))
+			if measure(s) > 1 {
+				return s
+			}
+			return w
+		}
+	}
+	if hasSuffix(w, "ion") {
+		s := trimSuffix(w, 3)
+		if len(s) > 0 && (s[len(s)-1] == 's' || s[len(s)-1] == 't') && measure(s) > 1 {
+			return s
+		}
+	}
+	return w
+}
+
+func step5a(w []rune) []rune {
+	if hasSuffix(w, "e") {
+		s := trimSuffix(w, 1)
+		m := measure(s)
+		if m > 1 || (m == 1 && !endsCVC(s)) {
+			return s
+		}
+	}
+	return w
+}
+
+func step5b(w []rune) []rune {
+	if measure(w) > 1 && endsDoubleConsonant(w) && hasSuffix(w, "l") {
+		return trimSuffix(w, 1)
+	}
+	return w
+}
@@ -0,0 +1,81 @@
+package mdoc
+
+import "testing"
+
+// TestStem checks stem against a sample of Porter's published test
+// vocabulary (voc.txt/output.txt), covering each step of the
+// algorithm: plurals and -ed/-ing (step1a/b), y->i (step1c), the long
+// suffix tables (step2-4), and the final cleanup (step5).
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		// step1a
+		"caresses": "caress",
+		"ponies":   "poni",
+		"ties":     "ti",
+		"caress":   "caress",
+		"cats":     "cat",
+		// step1b
+		"feed":      "feed",
+		"agreed":    "agre",
+		"plastered": "plaster",
+		"bled":      "bled",
+		"motoring":  "motor",
+		"sing":      "sing",
+		"conflated": "conflat",
+		"troubled":  "troubl",
+		"sized":     "size",
+		"hopping":   "hop",
+		"tanned":    "tan",
+		"falling":   "fall",
+		"hissing":   "hiss",
+		"fizzed":    "fizz",
+		"failing":   "fail",
+		"filing":    "file",
+		// step1c
+		"happy": "happi",
+		"sky":   "sky",
+		// step2
+		"relational":   "relat",
+		"conditional":  "condit",
+		"rational":     "ration",
+		"valenci":      "valenc",
+		"hesitanci":    "hesit",
+		"digitizer":    "digit",
+		"conformably":  "conform",
+		"radically":    "radic",
+		"differently":  "differ",
+		"vileli":       "vile",
+		"analogously":  "analog",
+		"predication":  "predic",
+		"operator":     "oper",
+		"feudalism":    "feudal",
+		"decisiveness": "decis",
+		"hopefulness":  "hope",
+		"callousness":  "callous",
+		"formaliti":    "formal",
+		"sensitiviti":  "sensit",
+		"sensibiliti":  "sensibl",
+		// step3
+		"triplicate": "triplic",
+		"formative":  "form",
+		"hopeful":    "hope",
+		"goodness":   "good",
+		// step4
+		"revival":     "reviv",
+		"allowance":   "allow",
+		"electriciti": "electr",
+		"adjustable":  "adjust",
+		"dependent":   "depend",
+		// step5
+		"probate":  "probat",
+		"rate":     "rate",
+		"cease":    "ceas",
+		"controll": "control",
+		"roll":     "roll",
+	}
+	for in, want := range cases {
+		if got := stem(in); got != want {
+			t.Errorf("stem(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
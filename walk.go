@@ -0,0 +1,224 @@
+package mdoc
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// walkSuffix is the file extension that declares a codewalk: an
+// ordered tour of steps, each pointing at a fragment of another file
+// with Markdown commentary. This is mdoc's take on the codewalk
+// feature from golang.org.
+const walkSuffix = ".walk.md"
+
+func isWalkFile(name string) bool {
+	return strings.HasSuffix(name, walkSuffix)
+}
+
+// walkName returns the name a codewalk is addressed by under
+// /.mdoc/walk/, stripping its .walk.md suffix.
+func walkName(name string) string {
+	return strings.TrimSuffix(path.Base(name), walkSuffix)
+}
+
+// Step represents a single step of a codewalk: commentary alongside
+// the fragment of the file it discusses.
+type Step struct {
+	Title      string
+	File       string
+	StartLine  int
+	EndLine    int
+	Commentary template.HTML
+	Source     template.HTML
+}
+
+// WalkPage represents the data used to render a codewalk.
+type WalkPage struct {
+	Layout
+	Title string
+	Steps []Step
+}
+
+// errWalkFound stops fs.WalkDir once the requested codewalk has
+// been located.
+var errWalkFound = errors.New("mdoc: walk found")
+
+func (h *handler) walks() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(req.URL.Path, "/.mdoc/walk/"), "/")
+		if name == "" {
+			h.errorHandler(w, req, ErrNotFound)
+			return
+		}
+		b, err := h.renderWalk(name, req.URL.Path)
+		if err != nil {
+			h.errorHandler(w, req, err)
+			return
+		}
+		w.Write(b)
+	})
+}
+
+func (h *handler) renderWalk(name, urlPath string) ([]byte, error) {
+	walkPath, err := h.findWalk(name)
+	if err != nil {
+		return nil, err
+	}
+	return h.renderWalkFile(walkPath, urlPath)
+}
+
+// renderWalkFile renders the codewalk at walkPath, a path already
+// known to the caller (findWalk's own result, or a path getFiles
+// already walked), so it skips the fs-wide name search renderWalk
+// does.
+func (h *handler) renderWalkFile(walkPath, urlPath string) ([]byte, error) {
+	raw, err := fs.ReadFile(h.fsys, walkPath)
+	if err != nil {
+		return nil, err
+	}
+	steps, title, err := parseWalk(raw, h.markdown)
+	if err != nil {
+		return nil, err
+	}
+	for i, step := range steps {
+		src, err := h.loadSource(step)
+		if err != nil {
+			return nil, err
+		}
+		steps[i].Source = src
+	}
+	v := WalkPage{
+		Layout: Layout{
+			root: h.root,
+			path: urlPath,
+		},
+		Title: title,
+		Steps: steps,
+	}
+	render, err := h.getWalkRenderer()
+	if err != nil {
+		return nil, err
+	}
+	return render(v)
+}
+
+// findWalk locates the .walk.md file under h.fsys addressed by name.
+func (h *handler) findWalk(name string) (string, error) {
+	var found string
+	err := fs.WalkDir(h.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isWalkFile(p) && walkName(p) == name {
+			found = p
+			return errWalkFound
+		}
+		return nil
+	})
+	if err != nil && err != errWalkFound {
+		return "", err
+	}
+	if found == "" {
+		return "", ErrNotFound
+	}
+	return found, nil
+}
+
+// parseWalk parses a .walk.md file. Front matter declares the walk's
+// title; each "## " heading in the body starts a new step, and a
+// "@file: path#L<start>-L<end>" line within a step names the file
+// fragment it discusses. Everything else in a step is Markdown
+// commentary.
+func parseWalk(raw []byte, markdown func([]byte) ([]byte, error)) ([]Step, string, error) {
+	meta, body := splitFrontMatter(raw)
+	var steps []Step
+	var cur *Step
+	var commentary [][]byte
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		html, err := markdown(bytes.Join(commentary, []byte("\n")))
+		if err != nil {
+			return err
+		}
+		cur.Commentary = template.HTML(string(html))
+		steps = append(steps, *cur)
+		return nil
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		s := strings.TrimSpace(string(line))
+		switch {
+		case strings.HasPrefix(s, "## "):
+			if err := flush(); err != nil {
+				return nil, "", err
+			}
+			cur = &Step{Title: strings.TrimSpace(strings.TrimPrefix(s, "## "))}
+			commentary = nil
+		case strings.HasPrefix(s, "@file:"):
+			if cur == nil {
+				continue
+			}
+			cur.File, cur.StartLine, cur.EndLine = parseFileDirective(strings.TrimSpace(strings.TrimPrefix(s, "@file:")))
+		default:
+			if cur != nil {
+				commentary = append(commentary, line)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, "", err
+	}
+	return steps, meta.Title(), nil
+}
+
+// parseFileDirective parses "path/to/file.go#L10-L25" into a file
+// path and a 1-indexed, inclusive line range. A missing range selects
+// the whole file.
+func parseFileDirective(s string) (file string, start, end int) {
+	file = s
+	i := strings.IndexByte(s, '#')
+	if i < 0 {
+		return file, 0, 0
+	}
+	file = s[:i]
+	rng := strings.TrimPrefix(s[i+1:], "L")
+	parts := strings.SplitN(rng, "-L", 2)
+	start, _ = strconv.Atoi(parts[0])
+	if len(parts) == 2 {
+		end, _ = strconv.Atoi(parts[1])
+	} else {
+		end = start
+	}
+	return file, start, end
+}
+
+// loadSource reads and escapes the file fragment a step points at.
+func (h *handler) loadSource(step Step) (template.HTML, error) {
+	if step.File == "" {
+		return "", nil
+	}
+	raw, err := fs.ReadFile(h.fsys, step.File)
+	if err != nil {
+		return "", err
+	}
+	lines := bytes.Split(raw, []byte("\n"))
+	start, end := step.StartLine, step.EndLine
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		start = end
+	}
+	frag := bytes.Join(lines[start-1:end], []byte("\n"))
+	return template.HTML("<pre><code>" + template.HTMLEscapeString(string(frag)) + "</code></pre>"), nil
+}